@@ -0,0 +1,101 @@
+package rscanner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+const defaultMemoryThreshold = 32 << 20 // 32 MiB
+
+// seekerReaderAt adapts an io.ReadSeeker to io.ReaderAt by serializing
+// Seek+Read pairs with a mutex, since a ReadSeeker has no notion of
+// positioned reads and is not generally safe for concurrent use on its own.
+type seekerReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+func (s *seekerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(s.rs, p)
+}
+
+// NewScannerFromSeeker adapts an io.ReadSeeker, such as an *os.File opened
+// on a platform where it can't be trusted with concurrent ReadAt calls,
+// into a Scanner. Reads are serialized with a mutex, so the returned
+// Scanner is safe to use but gives up the concurrency a native
+// io.ReaderAt would allow.
+func NewScannerFromSeeker(rs io.ReadSeeker, size int64) *Scanner {
+	return NewScanner(&seekerReaderAt{rs: rs}, size)
+}
+
+// errReaderAt always fails with err, used to defer a setup failure in
+// NewScannerFromReader until the first Scan call, the same way every other
+// read failure reaches the caller through Scan and Err. It is always
+// paired with a size of 1 so NewScanner attempts a read instead of
+// concluding there is nothing to scan.
+type errReaderAt struct{ err error }
+
+func (e errReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return 0, e.err
+}
+
+// NewScannerFromReader adapts a plain io.Reader, such as a gzip.Reader
+// decompressing a rotated log, into a Scanner by reading it fully up
+// front, since reverse scanning needs positioned access that a bare Reader
+// can't offer. sizeHint only preallocates the in-memory buffer and need
+// not be exact; 0 is fine when the length is unknown, which is the common
+// case for a decompressed stream. The Scanner is always sized from the
+// bytes actually read from r, never from sizeHint.
+//
+// Content no larger than memThreshold (defaulting to 32 MiB if omitted) is
+// buffered in memory; larger content is spilled to a temporary file, which
+// is removed as soon as it is written and stays accessible through the
+// returned Scanner via its open file descriptor. Callers should Close the
+// returned Scanner once done with it to release that file descriptor; Close
+// is a no-op when the content was small enough to stay in memory.
+//
+// Any error encountered while buffering is not returned directly; it
+// surfaces from the first call to Scan, like any other read error.
+func NewScannerFromReader(r io.Reader, sizeHint int64, memThreshold ...int64) *Scanner {
+	threshold := int64(defaultMemoryThreshold)
+	if len(memThreshold) > 0 {
+		threshold = memThreshold[0]
+	}
+	if sizeHint < 0 {
+		sizeHint = 0
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, sizeHint))
+	n, err := io.CopyN(buf, r, threshold+1)
+	if err == nil {
+		// r has more than threshold bytes buffered already; spill the lot
+		// to disk instead of holding it all in memory.
+		f, ferr := os.CreateTemp("", "rscanner-*")
+		if ferr != nil {
+			return NewScanner(errReaderAt{ferr}, 1)
+		}
+		defer os.Remove(f.Name())
+
+		written, werr := io.Copy(f, io.MultiReader(buf, r))
+		if werr != nil {
+			f.Close()
+			return NewScanner(errReaderAt{werr}, 1)
+		}
+		sc := NewScanner(f, written)
+		sc.closer = f
+		return sc
+	}
+	if err != io.EOF {
+		return NewScanner(errReaderAt{err}, 1)
+	}
+
+	return NewScanner(bytes.NewReader(buf.Bytes()), n)
+}
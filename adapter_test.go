@@ -0,0 +1,114 @@
+package rscanner_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vallerion/rscanner"
+	"golang.org/x/exp/slices"
+)
+
+// Test that NewScannerFromSeeker scans the same lines, in the same order,
+// as a plain NewScanner over the same content.
+func TestNewScannerFromSeeker(t *testing.T) {
+	lines := generateLines(1, 50)
+	s := strings.Join(lines, "\n")
+
+	sc := rscanner.NewScannerFromSeeker(strings.NewReader(s), int64(len(s)))
+
+	var got []string
+	for sc.Scan() {
+		got = append(got, sc.Text())
+	}
+	require.Nil(t, sc.Err())
+
+	want := append([]string(nil), lines...)
+	slices.Reverse(want)
+	require.Equal(t, want, got)
+}
+
+// Test that NewScannerFromReader buffers small readers in memory and
+// scans them the same way NewScanner would.
+func TestNewScannerFromReaderInMemory(t *testing.T) {
+	lines := generateLines(1, 50)
+	s := strings.Join(lines, "\n")
+
+	sc := rscanner.NewScannerFromReader(strings.NewReader(s), int64(len(s)))
+
+	var got []string
+	for sc.Scan() {
+		got = append(got, sc.Text())
+	}
+	require.Nil(t, sc.Err())
+	require.Len(t, got, len(lines))
+}
+
+// Test that NewScannerFromReader spills to a temp file once the reader
+// exceeds the given memory threshold, and still scans correctly.
+func TestNewScannerFromReaderSpillsToDisk(t *testing.T) {
+	lines := generateLines(1, 50)
+	s := strings.Join(lines, "\n")
+
+	sc := rscanner.NewScannerFromReader(strings.NewReader(s), int64(len(s)), 1)
+
+	var got []string
+	for sc.Scan() {
+		got = append(got, sc.Text())
+	}
+	require.Nil(t, sc.Err())
+	require.Len(t, got, len(lines))
+}
+
+// Test that NewScannerFromReader is unaffected by a sizeHint that
+// undershoots or overshoots the reader's actual length: it must scan the
+// full content without dropping or duplicating anything.
+func TestNewScannerFromReaderSizeHintMismatch(t *testing.T) {
+	lines := generateLines(1, 50)
+	s := strings.Join(lines, "\n")
+
+	for _, hint := range []int64{0, int64(len(s)) - 6, int64(len(s)) + 100} {
+		sc := rscanner.NewScannerFromReader(strings.NewReader(s), hint)
+
+		var got []string
+		for sc.Scan() {
+			got = append(got, sc.Text())
+		}
+		require.Nil(t, sc.Err())
+
+		want := append([]string(nil), lines...)
+		slices.Reverse(want)
+		require.Equal(t, want, got)
+	}
+}
+
+// Test that Close releases the temp file backing a disk-spilled Scanner,
+// and is a harmless no-op for an in-memory one.
+func TestNewScannerFromReaderClose(t *testing.T) {
+	lines := generateLines(1, 50)
+	s := strings.Join(lines, "\n")
+
+	mem := rscanner.NewScannerFromReader(strings.NewReader(s), int64(len(s)))
+	require.NoError(t, mem.Close())
+
+	disk := rscanner.NewScannerFromReader(strings.NewReader(s), int64(len(s)), 1)
+	require.True(t, disk.Scan())
+	require.NoError(t, disk.Close())
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// Test that a failure while buffering surfaces via Scan/Err, not a panic
+// or a separate error return.
+func TestNewScannerFromReaderBufferError(t *testing.T) {
+	wantErr := errors.New("boom")
+	sc := rscanner.NewScannerFromReader(errReader{wantErr}, 10)
+
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), wantErr)
+}
@@ -3,12 +3,29 @@ package rscanner
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"math"
+	"reflect"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-type SplitFunc func(data []byte) (advance int, token []byte, err error)
+// SplitFunc is the signature of the split function used to tokenize the
+// input in reverse. The arguments are the tail-most window of the
+// remaining unprocessed data and a flag, atStart, that reports whether the
+// window reaches all the way back to offset 0 of the underlying reader, so
+// no more data can be loaded to the left of it. The return values are the
+// number of bytes of data that remain unprocessed to the left and the next
+// token to return to the user (taken from the right of that boundary),
+// plus an error, if any.
+//
+// A SplitFunc can return (0, nil, nil) to signal that it needs a bigger
+// window, in which case the Scanner loads more data to the left and tries
+// again, unless atStart was already true.
+type SplitFunc func(data []byte, atStart bool) (advance int, token []byte, err error)
 
 var (
 	ErrTooLong         = errors.New("rscanner.Scanner: token too long")
@@ -28,32 +45,66 @@ func NewScanner(r io.ReaderAt, readerSize int64) *Scanner {
 
 	return &Scanner{
 		bufSize:                  bufSize,
-		maxTokenSize:             bufio.MaxScanTokenSize,
+		max:                      bufio.MaxScanTokenSize,
 		split:                    ScanLines,
+		splitTrimsDelimiters:     true,
 		start:                    bufSize,
 		end:                      bufSize,
 		rOffset:                  readerSize,
+		initialSize:              readerSize,
 		r:                        r,
 		maxConsecutiveEmptyReads: defaultMaxConsecutiveEmptyReads,
 	}
 }
 
+// scanLinesPtr identifies ScanLines by its code pointer, so Split can tell
+// whether the installed SplitFunc is exactly the package's own ScanLines
+// without relying on unsound pointer-arithmetic tricks on the token itself.
+var scanLinesPtr = reflect.ValueOf(ScanLines).Pointer()
+
 type Scanner struct {
-	maxTokenSize             int         // Maximum size of a token.
-	token                    []byte      // Last token returned by split.
-	buf                      []byte      // Buffer used as argument to split.
-	bufSize                  int         // Size of the buffer.
-	start, end               int         // Start and End of data to be scanned in buf.
-	rOffset                  int64       // Reader offset.
-	r                        io.ReaderAt // The reader provided by the user.
-	split                    SplitFunc   // The function to split the tokens, can be provided by user.
-	err                      error       // Sticky error.
-	done                     bool        // Scan has finished.
-	scanCalled               bool        // Scan has been called; buffer is in use.
-	maxConsecutiveEmptyReads int         // How many empty r reads allowed.
+	max                      int          // Maximum size of a token (the growth ceiling).
+	token                    []byte       // Last token returned by split.
+	buf                      []byte       // Buffer used as argument to split.
+	bufSize                  int          // Size of the buffer.
+	start, end               int          // Start and End of data to be scanned in buf.
+	rOffset                  int64        // Reader offset.
+	initialSize              int64        // Size of the reader at construction; where tailing resumes from.
+	r                        io.ReaderAt  // The reader provided by the user.
+	split                    SplitFunc    // The function to split the tokens, can be provided by user.
+	splitTrimsDelimiters     bool         // True only when split is the package's own ScanLines.
+	pos                      int64        // Absolute offset of the last token returned.
+	err                      error        // Sticky error.
+	done                     bool         // Scan has finished.
+	scanCalled               bool         // Scan has been called; buffer is in use.
+	maxConsecutiveEmptyReads int          // How many empty r reads allowed.
+	errorHandler             ErrorHandler // Decides whether to retry a failed read, if set.
+	closer                   io.Closer    // Resource to release on Close, if any. See adapter.go.
+
+	// Tail mode, set up by TailContext and entered once the reverse drain
+	// above reaches offset 0. See tail.go.
+	ctx          context.Context
+	sizeFunc     SizeFunc
+	pollInterval time.Duration
+	tailing      bool
+	fOffset      int64 // Absolute offset of the next unread byte.
+	knownSize    int64 // Last size reported by sizeFunc.
+	fBuf         []byte
+	fStart, fEnd int // Unread forward data in fBuf is fBuf[fStart:fEnd].
 }
 
 func (bs *Scanner) Scan() bool {
+	if bs.tailing {
+		// Same fail-once contract as the reverse path below: once erred,
+		// stay erred, with the sole exception of ErrTruncated, which
+		// scanForward treats as recoverable and clears itself.
+		if bs.err != nil && bs.err != ErrTruncated {
+			bs.token = nil
+			return false
+		}
+		return bs.scanForward()
+	}
+
 	// First check if scanner is done or there is an error.
 	if bs.done || bs.err != nil {
 		bs.token = nil
@@ -79,17 +130,24 @@ func (bs *Scanner) Scan() bool {
 			// but we read < N.
 			// So we run a loop until we fully read it.
 			off := bs.rOffset
-			for left, emptyReads := 0, 0; left < bs.start; {
+			for left, emptyReads, errAttempt := 0, 0, 0; left < bs.start; {
 				n, err := bs.r.ReadAt(bs.buf[left:bs.start], off)
 				// If reader misbehave.
 				if n < 0 || n > bs.start {
 					bs.setErr(ErrBadReadCount)
 					return false
 				}
+				if err != nil && err != io.EOF && bs.errorHandler != nil {
+					errAttempt++
+					if errAttempt <= bs.maxConsecutiveEmptyReads && bs.errorHandler(err, errAttempt) {
+						continue
+					}
+				}
 				if err != nil {
 					bs.setErr(err)
 					return false
 				}
+				errAttempt = 0
 				left += n
 				off += int64(n)
 
@@ -105,7 +163,7 @@ func (bs *Scanner) Scan() bool {
 			bs.start = 0
 		}
 
-		advance, token, err := bs.split(bs.buf[bs.start:bs.end])
+		advance, token, err := bs.split(bs.buf[bs.start:bs.end], bs.rOffset == 0)
 		if err != nil {
 			bs.setErr(err)
 			return false
@@ -126,20 +184,50 @@ func (bs *Scanner) Scan() bool {
 		// If advance>0 and token is nil when token is empty string.
 		// If token is not nil and advance=0 when token was found on beginning of the buf.
 		if advance > 0 || token != nil {
+			// advance is the index of the match itself, so for most split
+			// functions (ScanBytes, ScanRunes, ScanWords, and any
+			// user-provided SplitFunc) it's also where the returned token
+			// starts. ScanLines is the one exception: it trims the
+			// delimiter off the edges of data[advance:], so its token can
+			// start further right than advance. We can only prove that
+			// subslice relationship for our own ScanLines, so only it gets
+			// the cap-arithmetic correction; any other split func could
+			// hand back a token that isn't a subslice of bs.buf at all
+			// (the package's own errorRune included), and guessing its
+			// position from capacities would be unsound.
+			tokenStart := bs.start + advance
+			if bs.splitTrimsDelimiters && token != nil {
+				if off := cap(bs.buf) - cap(token); off >= tokenStart && off+len(token) <= bs.end {
+					tokenStart = off
+				}
+			}
+			bs.pos = bs.rOffset + int64(tokenStart)
 			bs.end = bs.start + advance
 			return true
 		}
 
 		if bs.rOffset == 0 {
 			if bs.start < bs.end {
-				bs.token = bytes.Trim(bs.buf[bs.start:bs.end], "\r\n")
-				bs.done = true
+				trimmed := bytes.TrimLeft(bs.buf[bs.start:bs.end], "\r\n")
+				leading := (bs.end - bs.start) - len(trimmed)
+				bs.token = bytes.TrimRight(trimmed, "\r\n")
+				bs.pos = bs.rOffset + int64(bs.start+leading)
+				bs.start, bs.end = 0, 0
+				if bs.sizeFunc == nil {
+					bs.done = true
+				}
 				return true
-			} else {
-				bs.token = nil
-				bs.done = true
-				return false
 			}
+
+			bs.token = nil
+			if bs.sizeFunc != nil {
+				bs.tailing = true
+				bs.fOffset = bs.initialSize
+				bs.knownSize = bs.initialSize
+				return bs.scanForward()
+			}
+			bs.done = true
+			return false
 		}
 
 		// Here we need more data to be loaded.
@@ -158,7 +246,7 @@ func (bs *Scanner) Scan() bool {
 
 		// Second we can increase buf size.
 		if bs.start == 0 {
-			if bs.bufSize >= bs.maxTokenSize || bs.bufSize > math.MaxInt/2 {
+			if bs.bufSize >= bs.max || bs.bufSize > math.MaxInt/2 {
 				bs.setErr(ErrTooLong)
 				return false
 			}
@@ -167,14 +255,27 @@ func (bs *Scanner) Scan() bool {
 			if newSize == 0 {
 				newSize = defaultBufSize
 			}
-			if newSize > bs.maxTokenSize {
-				newSize = bs.maxTokenSize
+			if newSize > bs.max {
+				newSize = bs.max
+			}
+			// width is the real leftover data still pending in buf, which
+			// split functions like ScanBytes/ScanRunes/ScanWords routinely
+			// shrink to less than a full buffer (or to nothing) before more
+			// data is needed. fill is how much of the grown buffer we can
+			// actually load from the reader; it is capped by rOffset
+			// because a partial or empty width means some of the "old"
+			// buffer space was already handed out as tokens and must not
+			// be re-read.
+			width := bs.end - bs.start
+			fill := newSize - width
+			if int64(fill) > bs.rOffset {
+				fill = int(bs.rOffset)
 			}
 			newBuf := make([]byte, newSize)
-			copy(newBuf[newSize-(bs.end-bs.start):newSize], bs.buf[bs.start:bs.end])
+			copy(newBuf[fill:fill+width], bs.buf[bs.start:bs.end])
 			bs.buf = newBuf
-			bs.start = newSize - bs.bufSize
-			bs.end = newSize
+			bs.start = fill
+			bs.end = fill + width
 			bs.bufSize = newSize
 		}
 	}
@@ -191,6 +292,16 @@ func (bs *Scanner) Err() error {
 	return bs.err
 }
 
+// Close releases any resource the Scanner owns, such as the temporary file
+// NewScannerFromReader spills large content to. It is a no-op for Scanners
+// that don't own one, so it is always safe to call.
+func (bs *Scanner) Close() error {
+	if bs.closer == nil {
+		return nil
+	}
+	return bs.closer.Close()
+}
+
 func (bs *Scanner) Bytes() []byte {
 	return bs.token
 }
@@ -199,21 +310,54 @@ func (bs *Scanner) Text() string {
 	return string(bs.token)
 }
 
-func (bs *Scanner) Buffer(buf []byte) {
+// Position returns the absolute byte offset in the underlying reader at
+// which the most recently returned token begins. It is meant for resuming
+// reverse scanning later, e.g. via NewScanner and decreasing readerSize to
+// Position(), or for indexing a log file by line offset.
+func (bs *Scanner) Position() int64 {
+	return bs.pos
+}
+
+// RemainingBytes returns the number of bytes to the left of the most
+// recently returned token that have not been scanned yet.
+func (bs *Scanner) RemainingBytes() int64 {
+	return bs.rOffset + int64(bs.end-bs.start)
+}
+
+// Buffer sets the initial buffer to use when scanning. If max is given, it
+// also sets the maximum size of buffer that may be allocated while
+// scanning grows it; the actual ceiling is the larger of max and cap(buf).
+// Without max, the ceiling is left as it is: bufio.MaxScanTokenSize as set
+// by NewScanner, unless MaxTokenSize changed it. Buffer panics if max is smaller than cap(buf), or if it is
+// called after scanning has started.
+func (bs *Scanner) Buffer(buf []byte, max ...int) {
 	if bs.scanCalled {
 		panic("Buffer called after Scan")
 	}
+	if len(max) > 1 {
+		panic("rscanner.Scanner: Buffer takes at most one max argument")
+	}
+	if len(max) == 1 && max[0] < cap(buf) {
+		panic("rscanner.Scanner: max smaller than cap(buf)")
+	}
+
 	bs.buf = buf[0:cap(buf)]
 	bs.bufSize = cap(buf)
 	bs.start = bs.bufSize
 	bs.end = bs.bufSize
+
+	if len(max) == 1 {
+		bs.max = max[0]
+	}
 }
 
+// MaxTokenSize sets the maximum size of buffer that may be allocated while
+// scanning grows it; it is an alias for the max argument of Buffer.
 func (bs *Scanner) MaxTokenSize(max int) {
 	if bs.scanCalled {
 		panic("Buffer called after Scan")
 	}
-	bs.maxTokenSize = max
+	bs.max = max
 }
 
 func (bs *Scanner) Split(split SplitFunc) {
@@ -221,6 +365,7 @@ func (bs *Scanner) Split(split SplitFunc) {
 		panic("Split called after Scan")
 	}
 	bs.split = split
+	bs.splitTrimsDelimiters = reflect.ValueOf(split).Pointer() == scanLinesPtr
 }
 
 func (bs *Scanner) setErr(err error) {
@@ -233,7 +378,22 @@ func (bs *Scanner) MaxConsecutiveEmptyReads(v int) {
 	bs.maxConsecutiveEmptyReads = v
 }
 
-func ScanLines(data []byte) (advance int, token []byte, err error) {
+// ErrorHandler decides whether Scan should retry a read that failed with
+// err. attempt counts consecutive failed attempts against that same read,
+// starting at 1. It returns true to retry.
+type ErrorHandler func(err error, attempt int) (retry bool)
+
+// OnReadError installs a handler invoked whenever the underlying reader's
+// ReadAt fails with an error other than io.EOF, so transient failures
+// (e.g. a network read timing out) can be retried instead of ending the
+// scan. Retries are still bounded by MaxConsecutiveEmptyReads to prevent
+// looping forever against a reader that never recovers. Without a
+// handler, Scan fails fast on the first error, as before.
+func (bs *Scanner) OnReadError(h ErrorHandler) {
+	bs.errorHandler = h
+}
+
+func ScanLines(data []byte, atStart bool) (advance int, token []byte, err error) {
 	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
 		return i, bytes.Trim(data[i:], "\r\n"), nil
 	}
@@ -241,3 +401,84 @@ func ScanLines(data []byte) (advance int, token []byte, err error) {
 	// Request more data.
 	return 0, nil, nil
 }
+
+// ScanBytes is a split function for a Scanner that returns each byte as a
+// token, working backwards from the end of the reader.
+func ScanBytes(data []byte, atStart bool) (advance int, token []byte, err error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	i := len(data) - 1
+	return i, data[i:], nil
+}
+
+// errorRune is the UTF-8 encoding of utf8.RuneError, returned by ScanRunes
+// in place of a byte sequence that cannot be decoded.
+var errorRune = []byte(string(utf8.RuneError))
+
+// ScanRunes is a split function for a Scanner that returns each UTF-8
+// encoded rune as a token, working backwards from the end of the reader.
+// The sequence of runes returned is equivalent to that from a reverse
+// range loop over the input as a string, which means that erroneous UTF-8
+// encodings translate to U+FFFD = "\xef\xbf\xbd". Because of the Scan
+// interface, this makes it impossible for the client to distinguish
+// correctly encoded replacement runes from encoding errors.
+func ScanRunes(data []byte, atStart bool) (advance int, token []byte, err error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	r, width := utf8.DecodeLastRune(data)
+	if r == utf8.RuneError && width == 1 {
+		// The rune may be cut off by the left edge of the window; ask for
+		// more data unless we already know nothing precedes it.
+		if !atStart && len(data) < utf8.UTFMax {
+			return 0, nil, nil
+		}
+
+		return len(data) - 1, errorRune, nil
+	}
+
+	i := len(data) - width
+	return i, data[i:], nil
+}
+
+// ScanWords is a split function for a Scanner that returns each
+// space-separated word of text, with surrounding spaces deleted, working
+// backwards from the end of the reader. It will never return an empty
+// string. The definition of space is set by unicode.IsSpace.
+func ScanWords(data []byte, atStart bool) (advance int, token []byte, err error) {
+	// Skip spaces adjoining the right edge of the window, i.e. trailing
+	// the word we are about to scan.
+	end := len(data)
+	for end > 0 {
+		r, width := utf8.DecodeLastRune(data[:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= width
+	}
+
+	// Scan backwards, marking the start of the word.
+	start := end
+	for start > 0 {
+		r, width := utf8.DecodeLastRune(data[:start])
+		if unicode.IsSpace(r) {
+			break
+		}
+		start -= width
+	}
+
+	if start == end {
+		// Nothing but space in this window.
+		return 0, nil, nil
+	}
+
+	if start == 0 && !atStart {
+		// The word may continue further left than this window.
+		return 0, nil, nil
+	}
+
+	return start, data[start:end], nil
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 // slowReaderAt is io.ReaderAt that returns only a few bytes at a time,
@@ -150,6 +151,34 @@ func TestScanBufReachMaxTokenSize(t *testing.T) {
 	require.ErrorIs(t, sc.Err(), rscanner.ErrTooLong)
 }
 
+// Test that Buffer's second, variadic argument sets the growth ceiling
+// independently of MaxTokenSize.
+func TestBufferWithMax(t *testing.T) {
+	tokenSize, bufSize := 15, 10
+	lines := generateLines(tokenSize-1, 3)
+
+	slices.Reverse(lines)
+	s := strings.Join(lines, "\n")
+
+	r := strings.NewReader(s)
+	sc := rscanner.NewScanner(&slowReaderAt{1, r}, int64(len(s)))
+	sc.Buffer(make([]byte, bufSize), tokenSize)
+
+	require.True(t, sc.Scan())
+	require.NotEmpty(t, sc.Bytes())
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), rscanner.ErrTooLong)
+}
+
+// Test that Buffer panics when max is smaller than cap(buf).
+func TestBufferPanicsOnMaxTooSmall(t *testing.T) {
+	sc := rscanner.NewScanner(strings.NewReader("abc"), 3)
+
+	require.Panics(t, func() {
+		sc.Buffer(make([]byte, 10), 5)
+	})
+}
+
 // Test when user provided buffer is small.
 func TestScanSmallInitBuf(t *testing.T) {
 	bufSize := 10
@@ -313,7 +342,7 @@ func TestSplitError(t *testing.T) {
 	// Create a split function that delivers a little data, then a predictable error.
 	numSplits := 0
 	const okCount = 7
-	errorSplit := func(data []byte) (advance int, token []byte, err error) {
+	errorSplit := func(data []byte, atStart bool) (advance int, token []byte, err error) {
 		if numSplits >= okCount {
 			return 0, nil, splitError
 		}
@@ -337,7 +366,7 @@ func TestSplitError(t *testing.T) {
 func TestSplitNegativeAdvance(t *testing.T) {
 	numSplits := 0
 	const okCount = 7
-	errorSplit := func(data []byte) (advance int, token []byte, err error) {
+	errorSplit := func(data []byte, atStart bool) (advance int, token []byte, err error) {
 		if numSplits >= okCount {
 			return -1, data[len(data)-1:], nil
 		}
@@ -363,7 +392,7 @@ func TestSplitAdvanceMoreThanBuffer(t *testing.T) {
 	numSplits := 0
 	const okCount = 7
 	const bufSize = 10
-	errorSplit := func(data []byte) (advance int, token []byte, err error) {
+	errorSplit := func(data []byte, atStart bool) (advance int, token []byte, err error) {
 		if numSplits >= okCount {
 			return bufSize + 1, data[len(data)-1:], nil
 		}
@@ -388,7 +417,7 @@ func TestSplitAdvanceMoreThanBuffer(t *testing.T) {
 
 func TestSplitReturnAlwaysNothing(t *testing.T) {
 	maxConsecutiveEmptyReads := 1
-	errorSplit := func(data []byte) (advance int, token []byte, err error) {
+	errorSplit := func(data []byte, atStart bool) (advance int, token []byte, err error) {
 		return 0, nil, nil
 	}
 	// Read the data.
@@ -416,6 +445,72 @@ func TestNonEOFWithEmptyRead(t *testing.T) {
 	require.ErrorIs(t, scanner.Err(), io.ErrUnexpectedEOF)
 }
 
+// flakyReaderAt fails the first failures calls to ReadAt with ErrTimeout,
+// then delegates to buf.
+type flakyReaderAt struct {
+	failures int
+	calls    int
+	buf      io.ReaderAt
+}
+
+func (f *flakyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return 0, iotest.ErrTimeout
+	}
+	return f.buf.ReadAt(p, off)
+}
+
+// Test that an ErrorHandler installed via OnReadError lets Scan recover
+// from a few transient read errors and still deliver the complete reverse
+// token sequence.
+func TestOnReadErrorRetries(t *testing.T) {
+	lines := generateLines(1, 50)
+	s := strings.Join(lines, "\n")
+
+	r := &flakyReaderAt{failures: 3, buf: strings.NewReader(s)}
+	sc := rscanner.NewScanner(r, int64(len(s)))
+
+	var attempts []int
+	sc.OnReadError(func(err error, attempt int) bool {
+		require.ErrorIs(t, err, iotest.ErrTimeout)
+		attempts = append(attempts, attempt)
+		return true
+	})
+
+	var got []string
+	for sc.Scan() {
+		got = append(got, sc.Text())
+	}
+	require.Nil(t, sc.Err())
+	require.Len(t, got, len(lines))
+	require.Equal(t, []int{1, 2, 3}, attempts)
+}
+
+// Test that retries still give up once MaxConsecutiveEmptyReads is
+// exceeded, so a handler that always asks to retry can't loop forever.
+func TestOnReadErrorGivesUp(t *testing.T) {
+	r := &flakyReaderAt{failures: 1000, buf: strings.NewReader("abc")}
+	sc := rscanner.NewScanner(r, 3)
+	sc.MaxConsecutiveEmptyReads(2)
+	sc.OnReadError(func(err error, attempt int) bool {
+		return true
+	})
+
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), iotest.ErrTimeout)
+}
+
+// Test that without an ErrorHandler, Scan keeps failing fast on the first
+// read error, as before.
+func TestReadErrorFailsFastWithoutHandler(t *testing.T) {
+	r := &flakyReaderAt{failures: 1, buf: strings.NewReader("abc")}
+	sc := rscanner.NewScanner(r, 3)
+
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), iotest.ErrTimeout)
+}
+
 // Test that Scanner.Scan finishes if we have endless empty reads.
 type endlessZeros struct{}
 
@@ -430,3 +525,195 @@ func TestEndlessReader(t *testing.T) {
 	require.False(t, s.Scan())
 	require.ErrorIs(t, s.Err(), io.ErrNoProgress)
 }
+
+// scanTests mirrors bufio's own scan_test.go table, including an invalid
+// UTF-8 byte and a correctly encoded replacement rune.
+var scanTests = []string{
+	"",
+	"a",
+	"¼",
+	"☹",
+	"\x81", // UTF-8 error
+	"�",    // correctly encoded RuneError
+	"abcdefgh",
+	"abc def\n\t\tgh    ",
+	"abc¼☹\x81�日本語\x82abc",
+}
+
+var wordScanTests = []string{
+	"",
+	"a",
+	"abc def",
+	"abc\tdef\nghi\rjkl\fmno\vpqrstu ",
+	"日本語 words here",
+}
+
+// collectBufio runs a bufio.SplitFunc forward over s and returns the
+// emitted tokens in order.
+func collectBufio(split bufio.SplitFunc, s string) [][]byte {
+	sc := bufio.NewScanner(strings.NewReader(s))
+	sc.Split(split)
+
+	var out [][]byte
+	for sc.Scan() {
+		out = append(out, append([]byte(nil), sc.Bytes()...))
+	}
+	return out
+}
+
+// collectRscanner runs an rscanner.SplitFunc backward over s and returns
+// the emitted tokens in order.
+func collectRscanner(split rscanner.SplitFunc, s string) [][]byte {
+	sc := rscanner.NewScanner(strings.NewReader(s), int64(len(s)))
+	sc.Split(split)
+
+	var out [][]byte
+	for sc.Scan() {
+		out = append(out, append([]byte(nil), sc.Bytes()...))
+	}
+	return out
+}
+
+// Test that ScanBytes emits, in order, the exact reverse of what
+// bufio.ScanBytes emits on the same input.
+func TestScanBytes(t *testing.T) {
+	for _, s := range scanTests {
+		exp := collectBufio(bufio.ScanBytes, s)
+		slices.Reverse(exp)
+
+		require.Equal(t, exp, collectRscanner(rscanner.ScanBytes, s))
+	}
+}
+
+// Test that ScanRunes emits, in order, the exact reverse of what
+// bufio.ScanRunes emits on the same input.
+func TestScanRunes(t *testing.T) {
+	for _, s := range scanTests {
+		exp := collectBufio(bufio.ScanRunes, s)
+		slices.Reverse(exp)
+
+		require.Equal(t, exp, collectRscanner(rscanner.ScanRunes, s))
+	}
+}
+
+// Test that ScanWords emits, in order, the exact reverse of what
+// bufio.ScanWords emits on the same input.
+func TestScanWords(t *testing.T) {
+	for _, s := range wordScanTests {
+		exp := collectBufio(bufio.ScanWords, s)
+		slices.Reverse(exp)
+
+		require.Equal(t, exp, collectRscanner(rscanner.ScanWords, s))
+	}
+}
+
+// collectRscannerSmallBuf is like collectRscanner but forces the buffer to
+// grow mid-token: it starts from a 1-byte buffer and a reader that only
+// ever yields one byte per read, so the leftover window left after
+// emitting a token is routinely smaller than the full buffer at the
+// moment more data is needed.
+func collectRscannerSmallBuf(split rscanner.SplitFunc, s string) [][]byte {
+	sc := rscanner.NewScanner(&slowReaderAt{1, strings.NewReader(s)}, int64(len(s)))
+	sc.Buffer(make([]byte, 1), 1<<20)
+	sc.Split(split)
+
+	var out [][]byte
+	for sc.Scan() {
+		out = append(out, append([]byte(nil), sc.Bytes()...))
+	}
+	return out
+}
+
+// Test that ScanBytes still emits the exact reverse of bufio.ScanBytes when
+// the buffer has to grow mid-token.
+func TestScanBytesSmallBuffer(t *testing.T) {
+	for _, s := range scanTests {
+		exp := collectBufio(bufio.ScanBytes, s)
+		slices.Reverse(exp)
+
+		require.Equal(t, exp, collectRscannerSmallBuf(rscanner.ScanBytes, s))
+	}
+}
+
+// Test that ScanRunes still emits the exact reverse of bufio.ScanRunes when
+// the buffer has to grow mid-token.
+func TestScanRunesSmallBuffer(t *testing.T) {
+	for _, s := range scanTests {
+		exp := collectBufio(bufio.ScanRunes, s)
+		slices.Reverse(exp)
+
+		require.Equal(t, exp, collectRscannerSmallBuf(rscanner.ScanRunes, s))
+	}
+}
+
+// Test that ScanWords still emits the exact reverse of bufio.ScanWords when
+// the buffer has to grow mid-token.
+func TestScanWordsSmallBuffer(t *testing.T) {
+	for _, s := range wordScanTests {
+		exp := collectBufio(bufio.ScanWords, s)
+		slices.Reverse(exp)
+
+		require.Equal(t, exp, collectRscannerSmallBuf(rscanner.ScanWords, s))
+	}
+
+	words := strings.Repeat("word ", 2000)
+	exp := collectBufio(bufio.ScanWords, words)
+	slices.Reverse(exp)
+
+	require.Equal(t, exp, collectRscannerSmallBuf(rscanner.ScanWords, words))
+}
+
+// Test that Position() round-trips: seeking to it in the original reader
+// and scanning forward from there yields the same bytes as the token that
+// was just emitted.
+func TestPositionRoundTrips(t *testing.T) {
+	lines := generateLines(1, 50)
+	s := strings.Join(lines, "\n")
+
+	r := strings.NewReader(s)
+	sc := rscanner.NewScanner(&slowReaderAt{3, r}, int64(len(s)))
+
+	for sc.Scan() {
+		pos := sc.Position()
+		token := append([]byte(nil), sc.Bytes()...)
+
+		got := make([]byte, len(token))
+		n, err := r.ReadAt(got, pos)
+		require.NoError(t, err)
+		require.Equal(t, len(token), n)
+		require.Equal(t, token, got)
+	}
+	require.Nil(t, sc.Err())
+}
+
+// Test that Position() doesn't misreport itself for a custom SplitFunc
+// whose token isn't a subslice of the Scanner's internal buffer, as with the
+// replacement rune ScanRunes returns for invalid UTF-8.
+func TestPositionWithNonSubsliceToken(t *testing.T) {
+	s := strings.Repeat("x", 20)
+
+	sc := rscanner.NewScanner(strings.NewReader(s), int64(len(s)))
+	sc.Split(func(data []byte, atStart bool) (int, []byte, error) {
+		return 0, append([]byte(nil), "abc"...), nil
+	})
+
+	require.True(t, sc.Scan())
+	require.Zero(t, sc.Position())
+}
+
+// Test that RemainingBytes decreases to zero as the reader is drained.
+func TestRemainingBytes(t *testing.T) {
+	lines := generateLines(1, 20)
+	s := strings.Join(lines, "\n")
+
+	r := strings.NewReader(s)
+	sc := rscanner.NewScanner(r, int64(len(s)))
+
+	var prev int64 = int64(len(s))
+	for sc.Scan() {
+		remaining := sc.RemainingBytes()
+		require.LessOrEqual(t, remaining, prev)
+		prev = remaining
+	}
+	require.Zero(t, sc.RemainingBytes())
+}
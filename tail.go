@@ -0,0 +1,125 @@
+package rscanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrTruncated is reported by Scan, in tail mode, when the underlying
+// reader has shrunk since it was last read. A Scanner recovers from this on
+// its own: it resumes tailing from offset 0, reading whatever the reader now
+// holds from the top, so the next call to Scan keeps following the reader
+// instead of failing forever.
+var ErrTruncated = errors.New("rscanner.Scanner: reader was truncated")
+
+// SizeFunc reports the current size of the reader passed to a Scanner's
+// tail mode, e.g. by calling Stat on the underlying file.
+type SizeFunc func() (int64, error)
+
+// TailContext switches the Scanner into follow mode, like `tail -F`, once
+// it has drained everything available at construction time in reverse:
+// instead of finishing, Scan starts polling sizeFunc every poll interval
+// and yields newly appended lines, in order, as they show up, until ctx is
+// canceled. This makes Scanner viable as the backend for a log viewer that
+// needs to show the last N lines and then keep streaming.
+//
+// Lines appended while tailing are split the same way ScanLines splits
+// them, regardless of the Scanner's configured split function, since the
+// custom SplitFuncs in this package are defined in terms of the reverse
+// scan.
+func (bs *Scanner) TailContext(ctx context.Context, sizeFunc SizeFunc, poll time.Duration) *Scanner {
+	bs.ctx = ctx
+	bs.sizeFunc = sizeFunc
+	bs.pollInterval = poll
+	return bs
+}
+
+// scanForward implements Scan once the Scanner has switched to tail mode.
+func (bs *Scanner) scanForward() bool {
+	// ErrTruncated is recoverable by design: the caller keeps calling Scan,
+	// which resumes tailing from offset 0. Clear it here, at the start of
+	// that resumed attempt, so a fresh failure (context cancellation, a
+	// real ReadAt error) can actually stick instead of being swallowed by
+	// setErr's sticky-error guard and hidden behind the stale truncation
+	// notice forever.
+	if bs.err == ErrTruncated {
+		bs.err = nil
+	}
+
+	for {
+		if i := bytes.IndexByte(bs.fBuf[bs.fStart:bs.fEnd], '\n'); i >= 0 {
+			line := bs.fBuf[bs.fStart : bs.fStart+i]
+			bs.pos = bs.fOffset - int64(bs.fEnd) + int64(bs.fStart)
+			bs.fStart += i + 1
+			bs.token = bytes.TrimRight(line, "\r")
+			bs.err = nil
+			return true
+		}
+
+		// Compact the buffer before growing it further.
+		if bs.fStart > 0 {
+			copy(bs.fBuf, bs.fBuf[bs.fStart:bs.fEnd])
+			bs.fEnd -= bs.fStart
+			bs.fStart = 0
+		}
+
+		size, err := bs.sizeFunc()
+		if err != nil {
+			bs.setErr(err)
+			return false
+		}
+
+		if size < bs.fOffset {
+			// The file shrank, so whatever is there now can't be assumed
+			// to be a continuation of what we've already read. Restart
+			// from the top and surface a recoverable error; the next Scan
+			// call resumes tailing from offset 0 instead of failing
+			// forever.
+			bs.fOffset = 0
+			bs.knownSize = size
+			bs.fStart, bs.fEnd = 0, 0
+			bs.err = ErrTruncated
+			return false
+		}
+		bs.knownSize = size
+
+		if size == bs.fOffset {
+			select {
+			case <-bs.ctx.Done():
+				bs.setErr(bs.ctx.Err())
+				return false
+			case <-time.After(bs.pollInterval):
+				continue
+			}
+		}
+
+		select {
+		case <-bs.ctx.Done():
+			bs.setErr(bs.ctx.Err())
+			return false
+		default:
+		}
+
+		want := int(size - bs.fOffset)
+		if bs.fEnd+want > len(bs.fBuf) {
+			newBuf := make([]byte, bs.fEnd+want)
+			copy(newBuf, bs.fBuf[:bs.fEnd])
+			bs.fBuf = newBuf
+		}
+
+		n, err := bs.r.ReadAt(bs.fBuf[bs.fEnd:bs.fEnd+want], bs.fOffset)
+		if n < 0 || n > want {
+			bs.setErr(ErrBadReadCount)
+			return false
+		}
+		bs.fEnd += n
+		bs.fOffset += int64(n)
+		if err != nil && err != io.EOF {
+			bs.setErr(err)
+			return false
+		}
+	}
+}
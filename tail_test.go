@@ -0,0 +1,166 @@
+package rscanner_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vallerion/rscanner"
+)
+
+// growableBuffer is an io.ReaderAt backed by an in-memory byte slice that
+// can grow or shrink after the Scanner has started reading it, to exercise
+// tail mode.
+type growableBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (g *growableBuffer) ReadAt(p []byte, off int64) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if off >= int64(len(g.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, g.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (g *growableBuffer) Size() (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return int64(len(g.data)), nil
+}
+
+func (g *growableBuffer) Append(s string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.data = append(g.data, s...)
+}
+
+func (g *growableBuffer) Truncate(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.data = g.data[:n]
+}
+
+func (g *growableBuffer) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.data)
+}
+
+// Test that TailContext first drains the existing content in reverse, then
+// keeps yielding lines appended afterwards, in order.
+func TestTailContextDrainThenFollow(t *testing.T) {
+	buf := &growableBuffer{}
+	buf.Append("a\nb\nc")
+
+	sc := rscanner.NewScanner(buf, int64(buf.Len()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc.TailContext(ctx, buf.Size, 5*time.Millisecond)
+
+	for _, want := range []string{"c", "b", "a"} {
+		require.True(t, sc.Scan())
+		require.Equal(t, want, sc.Text())
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		buf.Append("d\n")
+	}()
+
+	require.True(t, sc.Scan())
+	require.Equal(t, "d", sc.Text())
+	require.Nil(t, sc.Err())
+
+	got := make([]byte, len("d"))
+	n, err := buf.ReadAt(got, sc.Position())
+	require.NoError(t, err)
+	require.Equal(t, "d", string(got[:n]))
+}
+
+// Test that Scan returns ctx.Err() promptly once the context is canceled
+// while tailing.
+func TestTailContextCancel(t *testing.T) {
+	buf := &growableBuffer{}
+	buf.Append("only")
+
+	sc := rscanner.NewScanner(buf, int64(buf.Len()))
+	ctx, cancel := context.WithCancel(context.Background())
+	sc.TailContext(ctx, buf.Size, 5*time.Millisecond)
+
+	require.True(t, sc.Scan())
+	require.Equal(t, "only", sc.Text())
+
+	cancel()
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), context.Canceled)
+
+	// Unlike ErrTruncated, a context cancellation isn't recoverable: a
+	// later Scan call must stay failed instead of silently re-attempting
+	// sizeFunc/ReadAt from scratch.
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), context.Canceled)
+}
+
+// Test that truncation is reported via ErrTruncated and that the Scanner
+// recovers, tailing the file from scratch afterwards.
+func TestTailContextTruncated(t *testing.T) {
+	buf := &growableBuffer{}
+	buf.Append("a\nbbbbbb")
+
+	sc := rscanner.NewScanner(buf, int64(buf.Len()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc.TailContext(ctx, buf.Size, 5*time.Millisecond)
+
+	require.True(t, sc.Scan())
+	require.Equal(t, "bbbbbb", sc.Text())
+	require.True(t, sc.Scan())
+	require.Equal(t, "a", sc.Text())
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		buf.Truncate(0)
+		buf.Append("new\n")
+	}()
+
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), rscanner.ErrTruncated)
+
+	require.True(t, sc.Scan())
+	require.Equal(t, "new", sc.Text())
+}
+
+// Test that a terminal condition hitting after a truncation is reported as
+// itself, not masked by the earlier, now-stale ErrTruncated.
+func TestTailContextErrorAfterTruncated(t *testing.T) {
+	buf := &growableBuffer{}
+	buf.Append("a\nbbbbbb")
+
+	sc := rscanner.NewScanner(buf, int64(buf.Len()))
+	ctx, cancel := context.WithCancel(context.Background())
+	sc.TailContext(ctx, buf.Size, 5*time.Millisecond)
+
+	require.True(t, sc.Scan())
+	require.Equal(t, "bbbbbb", sc.Text())
+	require.True(t, sc.Scan())
+	require.Equal(t, "a", sc.Text())
+
+	buf.Truncate(0)
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), rscanner.ErrTruncated)
+
+	cancel()
+	require.False(t, sc.Scan())
+	require.ErrorIs(t, sc.Err(), context.Canceled)
+}